@@ -57,6 +57,10 @@ type Config struct {
 		Token  string `yaml:"token" json:"-"` // intentionally hidden from logs
 	} `yaml:"vc"`
 
+	Jobs struct {
+		HistoryRetention int `yaml:"history_retention"`
+	} `yaml:"jobs"`
+
 	Boardwhite struct {
 		ChatID                   int64 `yaml:"chat_id"`
 		LeetCodeThreadID         int   `yaml:"leetcode_thread_id"`
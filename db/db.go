@@ -1,18 +1,23 @@
 package db
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log/slog"
+	"time"
 )
 
 var (
 	ErrKeyNotFound = errors.New("db: not found")
 )
 
+// restoreBatchSize bounds how many keys are written per transaction during
+// RestoreJson, so a crash mid-restore only loses the in-flight batch.
+const restoreBatchSize = 200
+
 type KV struct {
 	Key []byte `json:"key"`
 	Val []byte `json:"val"`
@@ -22,63 +27,197 @@ type DB interface {
 	Start(context.Context) error
 	Stop()
 	Do(context.Context, func(Tx) error) error
-	Dump(context.Context) ([]KV, error)
+	// Scan walks every key-value pair in the database, invoking fn once per
+	// pair. It does not buffer the database in memory, so fn should be cheap
+	// and should not retain the KV's byte slices past the call.
+	Scan(ctx context.Context, fn func(KV) error) error
 }
 
 type Tx interface {
 	Get(key []byte) ([]byte, error)
 	Set(key []byte, val []byte) error
+	Delete(key []byte) error
 }
 
-func DumpJson(ctx context.Context, db DB, writer io.Writer) error {
-	dump, err := db.Dump(ctx)
-	if err != nil {
-		return fmt.Errorf("dump db: %w", err)
+// DoWithDeadline runs fn inside db.Do, bounding how long the transaction may
+// hold locks to timeout. Past the deadline, the closure's next Get/Set
+// returns a wrapped context.DeadlineExceeded so fn can bail out cleanly and
+// the outer Do rolls back, rather than the transaction holding locks
+// indefinitely. Each call makes its own cancel channel, closed by a
+// time.AfterFunc timer that is always stopped via defer, so a long-lived DB
+// handle never leaks timers; the channel is not shared or reused across calls.
+//
+// The bound only applies at the next Tx.Get/Set/Delete call, so it only
+// protects well-behaved closures: a fn stuck on something that never touches
+// Tx (time.Sleep, a network call, unbounded CPU work) keeps the underlying
+// transaction open for as long as that call runs, deadline or not.
+func DoWithDeadline(ctx context.Context, database DB, timeout time.Duration, fn func(Tx) error) error {
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	expired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+
+	return database.Do(deadlineCtx, func(tx Tx) error {
+		return fn(&deadlineTx{Tx: tx, expired: expired})
+	})
+}
+
+// deadlineTx wraps a Tx so every Get/Set made after the deadline elapses
+// returns a wrapped context.DeadlineExceeded instead of reaching the
+// underlying transaction.
+type deadlineTx struct {
+	Tx
+	expired <-chan struct{}
+}
+
+func (tx *deadlineTx) Get(key []byte) ([]byte, error) {
+	if err := tx.checkDeadline(); err != nil {
+		return nil, fmt.Errorf("get %q: %w", key, err)
 	}
 
-	out := make(map[string]json.RawMessage, len(dump))
-	for _, kv := range dump {
-		out[string(kv.Key)] = kv.Val
+	return tx.Tx.Get(key)
+}
+
+func (tx *deadlineTx) Set(key []byte, val []byte) error {
+	if err := tx.checkDeadline(); err != nil {
+		return fmt.Errorf("set %q: %w", key, err)
 	}
 
-	data, err := json.Marshal(out)
-	if err != nil {
-		return fmt.Errorf("marshall dump: %w", err)
+	return tx.Tx.Set(key, val)
+}
+
+func (tx *deadlineTx) Delete(key []byte) error {
+	if err := tx.checkDeadline(); err != nil {
+		return fmt.Errorf("delete %q: %w", key, err)
 	}
 
-	_, err = writer.Write(data)
-	if err != nil {
-		return fmt.Errorf("write dump: %w", err)
+	return tx.Tx.Delete(key)
+}
+
+func (tx *deadlineTx) checkDeadline() error {
+	select {
+	case <-tx.expired:
+		return context.DeadlineExceeded
+	default:
+		return nil
 	}
+}
 
-	return nil
+// Progress reports how far a DumpJson/RestoreJson call has gotten, so callers
+// like a CLI or a Telegram admin command can render it as a progress bar.
+// TotalKeys is 0 when the total is not known upfront.
+type Progress struct {
+	KeysProcessed  int64
+	BytesProcessed int64
+	TotalKeys      int64
 }
 
-func RestoreJson(ctx context.Context, db DB, reader io.Reader) error {
-	data, err := io.ReadAll(reader)
+type ProgressFunc func(Progress)
+
+// ndjsonRecord.Key is a string, not []byte, so keys stay readable (and
+// greppable) in the dump instead of being base64-encoded by encoding/json.
+type ndjsonRecord struct {
+	Key string          `json:"k"`
+	Val json.RawMessage `json:"v"`
+}
+
+// DumpJson streams every key in db as a newline-delimited JSON record
+// ({"k":"...","v":...}, one per line) to writer, so the output can be piped
+// through gzip and restored one key at a time without buffering the whole
+// database in memory. onProgress may be nil.
+func DumpJson(ctx context.Context, db DB, writer io.Writer, onProgress ProgressFunc) error {
+	enc := json.NewEncoder(writer)
+
+	var keys, bytesWritten int64
+	err := db.Scan(ctx, func(kv KV) error {
+		if err := enc.Encode(ndjsonRecord{Key: string(kv.Key), Val: kv.Val}); err != nil {
+			return fmt.Errorf("encode record: %w", err)
+		}
+
+		keys++
+		bytesWritten += int64(len(kv.Key) + len(kv.Val))
+		if onProgress != nil {
+			onProgress(Progress{KeysProcessed: keys, BytesProcessed: bytesWritten})
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("read dump: %w", err)
+		return fmt.Errorf("dump db: %w", err)
 	}
 
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return fmt.Errorf("unmarshall dump: %w", err)
-	}
+	return nil
+}
 
-	dump := make([]KV, 0, len(raw))
-	for k, v := range raw {
-		dump = append(dump, KV{Key: []byte(k), Val: v})
-	}
-	return db.Do(ctx, func(tx Tx) error {
-		for _, kv := range dump {
-			if err := tx.Set(kv.Key, kv.Val); err != nil {
-				return err
+// RestoreJson reads newline-delimited JSON records produced by DumpJson from
+// reader and writes them back. Keys are applied in batches of
+// restoreBatchSize, each inside its own db.Do transaction, so a crash
+// mid-restore only loses the in-flight batch rather than the whole restore.
+// onProgress may be nil.
+func RestoreJson(ctx context.Context, db DB, reader io.Reader, onProgress ProgressFunc) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var keys, bytesRead int64
+	batch := make([]ndjsonRecord, 0, restoreBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := db.Do(ctx, func(tx Tx) error {
+			for _, rec := range batch {
+				if err := tx.Set([]byte(rec.Key), rec.Val); err != nil {
+					return err
+				}
 			}
-			slog.Info("set key", slog.String("key", string(kv.Key)))
+
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
+		batch = batch[:0]
 		return nil
-	})
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec ndjsonRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("unmarshal record: %w", err)
+		}
+		batch = append(batch, rec)
+
+		keys++
+		bytesRead += int64(len(line))
+		if onProgress != nil {
+			onProgress(Progress{KeysProcessed: keys, BytesProcessed: bytesRead})
+		}
+
+		if len(batch) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return fmt.Errorf("restore batch: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read dump: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("restore batch: %w", err)
+	}
+
+	return nil
 }
 
 func GetJson[T any](tx Tx, key string) (T, error) {
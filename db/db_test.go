@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -160,13 +161,27 @@ func testDB(t *testing.T, name string, database db.DB, restoreDB db.DB) {
 		})
 		require.NoError(t, err)
 
+		var totalKeys int64
+		err = database.Scan(ctx, func(db.KV) error {
+			totalKeys++
+			return nil
+		})
+		require.NoError(t, err)
+
 		var buf bytes.Buffer
 		require.Empty(t, buf)
-		err = db.DumpJson(ctx, database, &buf)
+		var dumpProgress []db.Progress
+		err = db.DumpJson(ctx, database, &buf, func(p db.Progress) {
+			dumpProgress = append(dumpProgress, p)
+		})
 		require.NoError(t, err)
 		require.NotEmpty(t, buf)
+		require.NotEmpty(t, dumpProgress)
+		// DumpJson streams the whole database, not just the keys set in this
+		// subtest, so compare against a fresh Scan rather than len(keys).
+		require.EqualValues(t, totalKeys, dumpProgress[len(dumpProgress)-1].KeysProcessed)
 
-		err = db.RestoreJson(ctx, restoreDB, &buf)
+		err = db.RestoreJson(ctx, restoreDB, &buf, nil)
 		require.NoError(t, err)
 		err = restoreDB.Do(ctx, func(tx db.Tx) error {
 			for _, key := range keys {
@@ -179,6 +194,41 @@ func testDB(t *testing.T, name string, database db.DB, restoreDB db.DB) {
 		require.NoError(t, err)
 	})
 
+	t.Run(name+" json backup multiple batches", func(t *testing.T) {
+		const numKeys = 450 // more than restoreBatchSize, to exercise multiple flushes
+		keyPrefix := "key3.multi."
+
+		err := database.Do(ctx, func(tx db.Tx) error {
+			for i := 0; i < numKeys; i++ {
+				err := db.SetJson(tx, fmt.Sprintf("%s%d", keyPrefix, i), i)
+				require.NoError(t, err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = db.DumpJson(ctx, database, &buf, nil)
+		require.NoError(t, err)
+
+		var restoreProgress []db.Progress
+		err = db.RestoreJson(ctx, restoreDB, &buf, func(p db.Progress) {
+			restoreProgress = append(restoreProgress, p)
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, restoreProgress)
+
+		err = restoreDB.Do(ctx, func(tx db.Tx) error {
+			for i := 0; i < numKeys; i++ {
+				val, err := db.GetJson[int](tx, fmt.Sprintf("%s%d", keyPrefix, i))
+				require.NoError(t, err)
+				require.Equal(t, i, val)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
 	t.Run(name+" transaction", func(t *testing.T) {
 		key := "key4"
 		err := database.Do(ctx, func(tx db.Tx) error {
@@ -234,4 +284,52 @@ func testDB(t *testing.T, name string, database db.DB, restoreDB db.DB) {
 		})
 		require.NoError(t, err)
 	})
+
+	t.Run(name+" deadline", func(t *testing.T) {
+		key := "key6"
+		err := database.Do(ctx, func(tx db.Tx) error {
+			return db.SetJson(tx, key, 0)
+		})
+		require.NoError(t, err)
+
+		var deadlineErr error
+		var deadlineWG sync.WaitGroup
+		deadlineWG.Add(1)
+		go func() {
+			defer deadlineWG.Done()
+			deadlineErr = db.DoWithDeadline(ctx, database, 20*time.Millisecond, func(tx db.Tx) error {
+				time.Sleep(100 * time.Millisecond)
+				_, err := db.GetJson[int](tx, key)
+				return err
+			})
+		}()
+
+		// Fire concurrent Do calls while the goroutine above is still stuck in
+		// its Sleep, and check that they succeed alongside the slow
+		// transaction.
+		var wg sync.WaitGroup
+		wg.Add(5)
+		for i := 0; i < 5; i++ {
+			//nolint:testifylint
+			go func(i int) {
+				defer wg.Done()
+				err := database.Do(ctx, func(tx db.Tx) error {
+					return db.SetJson(tx, fmt.Sprintf("key6.%d", i), i)
+				})
+				require.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		deadlineWG.Wait()
+		require.ErrorIs(t, deadlineErr, context.DeadlineExceeded)
+
+		err = database.Do(ctx, func(tx db.Tx) error {
+			val, err := db.GetJson[int](tx, key)
+			require.NoError(t, err)
+			require.Equal(t, 0, val)
+			return nil
+		})
+		require.NoError(t, err)
+	})
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
@@ -9,15 +10,25 @@ import (
 	"github.com/boar-d-white-foundation/drone/boardwhite"
 	"github.com/boar-d-white-foundation/drone/config"
 	"github.com/boar-d-white-foundation/drone/db"
+	"github.com/boar-d-white-foundation/drone/jobs"
 	"github.com/boar-d-white-foundation/drone/leetcode"
 	"github.com/boar-d-white-foundation/drone/tg"
 	"github.com/go-co-op/gocron/v2"
 )
 
-func StartDrone(ctx context.Context, cfg config.Config) error {
+// services bundles the long-lived dependencies shared by the running server
+// and the one-off CLI subcommands that need the real DB and Telegram service
+// (e.g. `drone jobs run`), so both build them the exact same way.
+type services struct {
+	DB         db.DB
+	TG         *tg.BoardwhiteService
+	Boardwhite *boardwhite.Service
+}
+
+func newServices(ctx context.Context, cfg config.Config) (services, func(), error) {
 	alerts, err := alert.NewManagerFromConfig(cfg)
 	if err != nil {
-		return err
+		return services{}, nil, err
 	}
 
 	//browser, cleanup, err := chrome.NewRemote(cfg.Rod.Host, cfg.Rod.Port)
@@ -30,23 +41,33 @@ func StartDrone(ctx context.Context, cfg config.Config) error {
 
 	tgService, err := tg.NewBoardwhiteServiceFromConfig(cfg)
 	if err != nil {
-		return err
+		return services{}, nil, err
 	}
 
 	database := db.NewBadgerDBFromConfig(cfg)
 	if err := database.Start(ctx); err != nil {
-		return err
+		return services{}, nil, err
 	}
-	defer database.Stop()
 
 	bw, err := boardwhite.NewServiceFromConfig(cfg, tgService, database, alerts, nil, lcClient)
+	if err != nil {
+		database.Stop()
+		return services{}, nil, err
+	}
+
+	return services{DB: database, TG: tgService, Boardwhite: bw}, func() { database.Stop() }, nil
+}
+
+func StartDrone(ctx context.Context, cfg config.Config) error {
+	svc, cleanup, err := newServices(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	bw.RegisterHandlers(ctx, tgService)
-	tgService.Start()
-	defer tgService.Stop()
+	svc.Boardwhite.RegisterHandlers(ctx, svc.TG)
+	svc.TG.Start()
+	defer svc.TG.Stop()
 	slog.Info("started tg handlers")
 
 	scheduler, err := gocron.NewScheduler(gocron.WithLocation(time.UTC))
@@ -54,123 +75,59 @@ func StartDrone(ctx context.Context, cfg config.Config) error {
 		return err
 	}
 
-	jobs, err := registerCronJobs(ctx, cfg, scheduler, bw)
-	if err != nil {
+	jm := jobs.NewManager(scheduler, svc.DB, cfg.Jobs.HistoryRetention)
+	if err := registerCronJobs(ctx, cfg, jm, svc.Boardwhite); err != nil {
 		return err
 	}
 
-	scheduler.Start()
+	// jm is not exposed through Telegram: no admin command handlers are
+	// registered for it. Outside this process, `drone jobs list` and `drone
+	// jobs run <name>` (drone/cli.go) list the jobs and run one of them; both
+	// work from jobSpecs directly rather than through jm, so a run started
+	// there is not recorded in job history and is not de-duplicated against a
+	// scheduled run. Pause, Resume and Reschedule have no operator command.
+
+	jm.Start()
 	slog.Info("started scheduler")
-	for _, jb := range jobs {
-		t, err := jb.NextRun()
+	for _, name := range jm.List() {
+		t, err := jm.NextRun(name)
 		if err != nil {
 			return err
 		}
-		slog.Info(
-			"scheduled job",
-			slog.String("name", jb.name),
-			slog.String("cron", jb.cron),
-			slog.String("next_run", t.String()),
-		)
+		slog.Info("scheduled job", slog.String("name", name), slog.String("next_run", t.String()))
 	}
 	<-ctx.Done()
-	return scheduler.Shutdown()
-}
-
-type job struct {
-	gocron.Job
-
-	name string
-	cron string
+	return jm.Stop()
 }
 
-func registerCronJobs(
-	ctx context.Context,
-	cfg config.Config,
-	scheduler gocron.Scheduler,
-	bw *boardwhite.Service,
-) ([]job, error) {
-	jobs := make([]job, 0)
-	jb, err := registerJob(ctx, scheduler, "PublishLCDaily", cfg.LeetcodeDaily.Cron, bw.PublishLCDaily)
-	if err != nil {
-		return nil, err
-	}
-	jobs = append(jobs, jb)
-
-	jb, err = registerJob(ctx, scheduler, "PublishLCChickensDaily", cfg.LeetcodeDaily.Cron, bw.PublishLCChickensDaily)
-	if err != nil {
-		return nil, err
+// jobSpecs lists every job the server runs on a schedule, so both
+// registerCronJobs and the `drone jobs run` CLI subcommand register and look
+// them up the same way.
+func jobSpecs(cfg config.Config, bw *boardwhite.Service) []struct {
+	Name string
+	Cron string
+	Func jobs.Func
+} {
+	return []struct {
+		Name string
+		Cron string
+		Func jobs.Func
+	}{
+		{"PublishLCDaily", cfg.LeetcodeDaily.Cron, bw.PublishLCDaily},
+		{"PublishLCChickensDaily", cfg.LeetcodeDaily.Cron, bw.PublishLCChickensDaily},
+		{"PublishLCRating", cfg.LeetcodeDaily.RatingCron, bw.PublishLCRating},
+		{"PublishLCChickensRating", cfg.LeetcodeDaily.RatingCron, bw.PublishLCChickensRating},
+		{"PublishNCDaily", cfg.NeetcodeDaily.Cron, bw.PublishNCDaily},
+		{"PublishNCRating", cfg.NeetcodeDaily.RatingCron, bw.PublishNCRating},
 	}
-	jobs = append(jobs, jb)
-
-	jb, err = registerJob(ctx, scheduler, "PublishLCRating", cfg.LeetcodeDaily.RatingCron, bw.PublishLCRating)
-	if err != nil {
-		return nil, err
-	}
-	jobs = append(jobs, jb)
-
-	jb, err = registerJob(
-		ctx,
-		scheduler,
-		"PublishLCChickensRating",
-		cfg.LeetcodeDaily.RatingCron,
-		bw.PublishLCChickensRating,
-	)
-	if err != nil {
-		return nil, err
-	}
-	jobs = append(jobs, jb)
-
-	jb, err = registerJob(ctx, scheduler, "PublishNCDaily", cfg.NeetcodeDaily.Cron, bw.PublishNCDaily)
-	if err != nil {
-		return nil, err
-	}
-	jobs = append(jobs, jb)
-
-	jb, err = registerJob(ctx, scheduler, "PublishNCRating", cfg.NeetcodeDaily.RatingCron, bw.PublishNCRating)
-	if err != nil {
-		return nil, err
-	}
-	jobs = append(jobs, jb)
-
-	return jobs, nil
-}
-
-func registerJob(
-	ctx context.Context,
-	s gocron.Scheduler,
-	name, cron string,
-	f func(context.Context) error,
-) (job, error) {
-	jb, err := s.NewJob(
-		gocron.CronJob(cron, false),
-		gocron.NewTask(wrapErrors(name, f), ctx),
-	)
-	if err != nil {
-		return job{}, err
-	}
-
-	return job{
-		Job:  jb,
-		name: name,
-		cron: cron,
-	}, nil
 }
 
-func wrapErrors(name string, f func(context.Context) error) func(context.Context) {
-	return func(ctx context.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				slog.Error("panic in cron task", slog.String("name", name), slog.Any("err", err))
-			}
-		}()
-
-		slog.Info("started cron task run", slog.String("name", name))
-		err := f(ctx)
-		if err != nil {
-			slog.Error("err in cron task", slog.String("name", name), slog.Any("err", err))
-			return
+func registerCronJobs(ctx context.Context, cfg config.Config, jm jobs.Manager, bw *boardwhite.Service) error {
+	for _, spec := range jobSpecs(cfg, bw) {
+		if err := jm.Register(ctx, spec.Name, spec.Cron, spec.Func); err != nil {
+			return fmt.Errorf("register job %s: %w", spec.Name, err)
 		}
-		slog.Info("finished cron task run", slog.String("name", name))
 	}
+
+	return nil
 }
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/boar-d-white-foundation/drone/config"
+	"github.com/boar-d-white-foundation/drone/db"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "drone",
+		Short:         "Boardwhite drone bot and ops tooling",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	root.AddCommand(newServeCmd())
+	root.AddCommand(newDBCmd())
+	root.AddCommand(newJobsCmd())
+	root.AddCommand(newConfigCmd())
+	return root
+}
+
+func loadConfig() (config.Config, error) {
+	return config.Load(config.Path())
+}
+
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func newServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the drone bot, cron jobs and Telegram handlers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signalContext()
+			defer cancel()
+
+			return StartDrone(ctx, cfg)
+		},
+	}
+}
+
+func newDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect or back up the drone database",
+	}
+
+	var out string
+	var gzipOut bool
+	dump := &cobra.Command{
+		Use:   "dump",
+		Short: "Dump the database as newline-delimited JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDBDump(cmd.Context(), out, gzipOut)
+		},
+	}
+	dump.Flags().StringVar(&out, "out", "", "file to write the dump to (default stdout)")
+	dump.Flags().BoolVar(&gzipOut, "gzip", false, "gzip-compress the dump")
+	cmd.AddCommand(dump)
+
+	var in string
+	var gzipIn bool
+	restore := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the database from a newline-delimited JSON dump",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDBRestore(cmd.Context(), in, gzipIn)
+		},
+	}
+	restore.Flags().StringVar(&in, "in", "", "file to read the dump from (default stdin)")
+	restore.Flags().BoolVar(&gzipIn, "gzip", false, "the dump is gzip-compressed")
+	cmd.AddCommand(restore)
+
+	return cmd
+}
+
+func runDBDump(ctx context.Context, out string, gzipOut bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	database := db.NewBadgerDBFromConfig(cfg)
+	if err := database.Start(ctx); err != nil {
+		return err
+	}
+	defer database.Stop()
+
+	writer := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", out, err)
+		}
+		defer f.Close()
+		writer = f
+	}
+
+	if gzipOut {
+		gz := gzip.NewWriter(writer)
+		defer gz.Close()
+		writer = gz
+	}
+
+	return db.DumpJson(ctx, database, writer, dumpProgressBar())
+}
+
+func runDBRestore(ctx context.Context, in string, gzipIn bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	database := db.NewBadgerDBFromConfig(cfg)
+	if err := database.Start(ctx); err != nil {
+		return err
+	}
+	defer database.Stop()
+
+	reader := io.Reader(os.Stdin)
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return fmt.Errorf("open %s: %w", in, err)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	if gzipIn {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("open gzip reader: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	return db.RestoreJson(ctx, database, reader, dumpProgressBar())
+}
+
+// dumpProgressBar renders dump/restore progress to stderr the way pg_dump
+// and pg_restore report rows processed, so piping the data through to a
+// file or gzip still shows liveness on a long-running backup.
+func dumpProgressBar() db.ProgressFunc {
+	return func(p db.Progress) {
+		fmt.Fprintf(os.Stderr, "\rprocessed %d keys (%d bytes)", p.KeysProcessed, p.BytesProcessed)
+	}
+}
+
+func newJobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect or run scheduled jobs",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the jobs the server would schedule",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			for _, spec := range jobSpecs(cfg, nil) {
+				fmt.Printf("%s\t%s\n", spec.Name, spec.Cron)
+			}
+
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a single job synchronously against the real DB and Telegram service",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJob(cmd.Context(), args[0])
+		},
+	})
+
+	return cmd
+}
+
+func runJob(ctx context.Context, name string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	svc, cleanup, err := newServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	for _, spec := range jobSpecs(cfg, svc.Boardwhite) {
+		if spec.Name != name {
+			continue
+		}
+
+		return spec.Func(ctx)
+	}
+
+	return fmt.Errorf("unknown job %q", name)
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print or validate the drone config",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the loaded config as JSON, with secrets redacted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(cfg.String())
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Load the config and report whether it is valid",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := loadConfig(); err != nil {
+				return err
+			}
+
+			fmt.Println("config is valid")
+			return nil
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,382 @@
+// Package jobs wraps a gocron.Scheduler with a named registry so jobs can be
+// listed, triggered, paused, and rescheduled at runtime, and persists every
+// execution to db.DB so operators can inspect job history without a restart.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boar-d-white-foundation/drone/db"
+	"github.com/go-co-op/gocron/v2"
+)
+
+var ErrNotFound = fmt.Errorf("jobs: not found")
+
+const (
+	historyPrefix           = "jobs/history/"
+	defaultHistoryRetention = 30
+)
+
+// Func is a job body, matching the signature of the Boardwhite cron task
+// methods registered in drone/bot.go (see jobSpecs).
+type Func func(context.Context) error
+
+// Run is one recorded execution of a job, persisted under
+// jobs/history/<name>/<start unix nano>.
+type Run struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"err,omitempty"`
+	Panic    string        `json:"panic,omitempty"`
+}
+
+// Manager is the runtime control surface for registered jobs: inspecting
+// schedule and history, and triggering, pausing, resuming or rescheduling a
+// job without restarting the process.
+type Manager interface {
+	// Register wires f into the scheduler under name/cron and makes it
+	// addressable by the rest of the Manager methods.
+	Register(ctx context.Context, name, cron string, f Func) error
+	List() []string
+	NextRun(name string) (time.Time, error)
+	LastRuns(ctx context.Context, name string, n int) ([]Run, error)
+	// TriggerNow runs the job immediately and records its outcome. If the
+	// scheduled cron firing (or another TriggerNow) is already running the
+	// job, it does not start a second execution; it waits for that run to
+	// finish and returns its result instead, so this can never double-post.
+	TriggerNow(ctx context.Context, name string) error
+	Pause(name string) error
+	Resume(name string) error
+	Reschedule(ctx context.Context, name, cron string) error
+	Start()
+	Stop() error
+}
+
+type registration struct {
+	// inflightMu guards inflightDone/inflightErr and is held across the
+	// "is a run already in progress" decision, so the decision and the act
+	// of marking a run in progress happen as one atomic step: a caller can
+	// never observe inflightDone as nil for a run that is actually in
+	// flight, because whoever sets it does so before releasing inflightMu.
+	inflightMu   sync.Mutex
+	inflightDone chan struct{}
+	inflightErr  error
+
+	name   string
+	cron   string
+	f      Func
+	job    gocron.Job
+	paused atomic.Bool
+}
+
+type manager struct {
+	mu        sync.RWMutex
+	scheduler gocron.Scheduler
+	database  db.DB
+	retention int
+	byName    map[string]*registration
+}
+
+// NewManager builds a Manager backed by scheduler for firing jobs and
+// database for persisting their run history. retention is the number of
+// recent runs kept per job; 0 falls back to defaultHistoryRetention.
+func NewManager(scheduler gocron.Scheduler, database db.DB, retention int) Manager {
+	if retention <= 0 {
+		retention = defaultHistoryRetention
+	}
+
+	return &manager{
+		scheduler: scheduler,
+		database:  database,
+		retention: retention,
+		byName:    make(map[string]*registration),
+	}
+}
+
+func (m *manager) Register(ctx context.Context, name, cron string, f Func) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byName[name]; ok {
+		return fmt.Errorf("jobs: register %q: already registered", name)
+	}
+
+	reg := &registration{name: name, cron: cron, f: f}
+	jb, err := m.scheduler.NewJob(
+		gocron.CronJob(cron, false),
+		gocron.NewTask(m.runTask(ctx, reg)),
+	)
+	if err != nil {
+		return fmt.Errorf("jobs: register %q: %w", name, err)
+	}
+
+	reg.job = jb
+	m.byName[name] = reg
+	return nil
+}
+
+func (m *manager) runTask(ctx context.Context, reg *registration) func() {
+	return func() {
+		if reg.paused.Load() {
+			slog.Info("skipped paused job", slog.String("name", reg.name))
+			return
+		}
+
+		if err := m.run(ctx, reg); err != nil {
+			slog.Error("err in job run", slog.String("name", reg.name), slog.Any("err", err))
+		}
+	}
+}
+
+// run executes reg.f and records the outcome. If another run is already
+// in flight (the scheduled cron firing or a prior TriggerNow), it does not
+// execute reg.f a second time; instead it waits for that run to finish and
+// returns its result, so a manual trigger racing the cron never double-posts
+// to the Boardwhite chat.
+//
+// Whether a run is already in flight and the act of claiming the right to
+// start one are decided as a single step under inflightMu, so a caller that
+// loses the race can never observe a nil inflightDone for a run that is
+// actually in progress.
+func (m *manager) run(ctx context.Context, reg *registration) error {
+	reg.inflightMu.Lock()
+	if done := reg.inflightDone; done != nil {
+		reg.inflightMu.Unlock()
+		<-done
+
+		reg.inflightMu.Lock()
+		defer reg.inflightMu.Unlock()
+		return reg.inflightErr
+	}
+
+	done := make(chan struct{})
+	reg.inflightDone = done
+	reg.inflightMu.Unlock()
+
+	slog.Info("started job run", slog.String("name", reg.name))
+	run := Run{Start: time.Now()}
+	err := m.execute(ctx, reg.f, &run)
+	run.End = time.Now()
+	run.Duration = run.End.Sub(run.Start)
+
+	if histErr := m.saveRun(ctx, reg.name, run); histErr != nil {
+		slog.Error("save job history", slog.String("name", reg.name), slog.Any("err", histErr))
+	}
+
+	reg.inflightMu.Lock()
+	reg.inflightErr = err
+	reg.inflightDone = nil
+	reg.inflightMu.Unlock()
+	close(done)
+
+	if err != nil {
+		return err
+	}
+
+	slog.Info("finished job run", slog.String("name", reg.name))
+	return nil
+}
+
+func (m *manager) execute(ctx context.Context, f Func, run *Run) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			run.Panic = fmt.Sprintf("%v\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic in job: %v", r)
+		}
+	}()
+
+	err = f(ctx)
+	if err != nil {
+		run.Err = err.Error()
+	}
+
+	return err
+}
+
+func (m *manager) saveRun(ctx context.Context, name string, run Run) error {
+	key := historyKey(name, run.Start)
+	err := m.database.Do(ctx, func(tx db.Tx) error {
+		return db.SetJson(tx, key, run)
+	})
+	if err != nil {
+		return fmt.Errorf("save run: %w", err)
+	}
+
+	return m.pruneHistory(ctx, name)
+}
+
+// pruneHistory drops the oldest runs for name beyond m.retention. History
+// keys embed the start time as unix nanos, so lexicographic order matches
+// chronological order.
+func (m *manager) pruneHistory(ctx context.Context, name string) error {
+	prefix := historyPrefix + name + "/"
+
+	var keys []string
+	err := m.database.Scan(ctx, func(kv db.KV) error {
+		if strings.HasPrefix(string(kv.Key), prefix) {
+			keys = append(keys, string(kv.Key))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("prune history: %w", err)
+	}
+
+	if len(keys) <= m.retention {
+		return nil
+	}
+
+	sort.Strings(keys)
+	stale := keys[:len(keys)-m.retention]
+
+	return m.database.Do(ctx, func(tx db.Tx) error {
+		for _, key := range stale {
+			if err := tx.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *manager) LastRuns(ctx context.Context, name string, n int) ([]Run, error) {
+	if _, err := m.get(name); err != nil {
+		return nil, fmt.Errorf("jobs: last runs: %w", err)
+	}
+
+	prefix := historyPrefix + name + "/"
+	var runs []Run
+	err := m.database.Scan(ctx, func(kv db.KV) error {
+		if !strings.HasPrefix(string(kv.Key), prefix) {
+			return nil
+		}
+
+		var run Run
+		if err := json.Unmarshal(kv.Val, &run); err != nil {
+			return fmt.Errorf("unmarshal run %q: %w", kv.Key, err)
+		}
+
+		runs = append(runs, run)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jobs: last runs %q: %w", name, err)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.After(runs[j].Start) })
+	if len(runs) > n {
+		runs = runs[:n]
+	}
+
+	return runs, nil
+}
+
+func (m *manager) List() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.byName))
+	for name := range m.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (m *manager) NextRun(name string) (time.Time, error) {
+	reg, err := m.get(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return reg.job.NextRun()
+}
+
+func (m *manager) TriggerNow(ctx context.Context, name string) error {
+	reg, err := m.get(name)
+	if err != nil {
+		return err
+	}
+
+	return m.run(ctx, reg)
+}
+
+func (m *manager) Pause(name string) error {
+	reg, err := m.get(name)
+	if err != nil {
+		return err
+	}
+
+	reg.paused.Store(true)
+	return nil
+}
+
+func (m *manager) Resume(name string) error {
+	reg, err := m.get(name)
+	if err != nil {
+		return err
+	}
+
+	reg.paused.Store(false)
+	return nil
+}
+
+func (m *manager) Reschedule(ctx context.Context, name, cron string) error {
+	reg, err := m.get(name)
+	if err != nil {
+		return err
+	}
+
+	jb, err := m.scheduler.Update(
+		reg.job.ID(),
+		gocron.CronJob(cron, false),
+		gocron.NewTask(m.runTask(ctx, reg)),
+	)
+	if err != nil {
+		return fmt.Errorf("jobs: reschedule %q: %w", name, err)
+	}
+
+	m.mu.Lock()
+	reg.cron = cron
+	reg.job = jb
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *manager) Start() {
+	m.scheduler.Start()
+}
+
+func (m *manager) Stop() error {
+	return m.scheduler.Shutdown()
+}
+
+func (m *manager) get(name string) (*registration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reg, ok := m.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+
+	return reg, nil
+}
+
+func historyKey(name string, start time.Time) string {
+	return fmt.Sprintf("%s%s/%d", historyPrefix, name, start.UnixNano())
+}
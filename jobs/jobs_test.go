@@ -0,0 +1,230 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boar-d-white-foundation/drone/db"
+	"github.com/boar-d-white-foundation/drone/jobs"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// neverCron is a cron expression that will not fire during a test run, so
+// tests exercise jobs via TriggerNow rather than racing real schedule ticks.
+const neverCron = "0 0 1 1 *"
+
+func newManager(t *testing.T) jobs.Manager {
+	t.Helper()
+
+	scheduler, err := gocron.NewScheduler()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = scheduler.Shutdown() })
+
+	return jobs.NewManager(scheduler, newMemDB(), 0)
+}
+
+func TestRegisterListNextRun(t *testing.T) {
+	t.Parallel()
+
+	jm := newManager(t)
+	ctx := context.Background()
+
+	err := jm.Register(ctx, "job-a", neverCron, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	err = jm.Register(ctx, "job-b", neverCron, func(context.Context) error { return nil })
+	require.NoError(t, err)
+
+	err = jm.Register(ctx, "job-a", neverCron, func(context.Context) error { return nil })
+	require.Error(t, err)
+
+	require.Equal(t, []string{"job-a", "job-b"}, jm.List())
+
+	_, err = jm.NextRun("job-a")
+	require.NoError(t, err)
+
+	_, err = jm.NextRun("missing")
+	require.ErrorIs(t, err, jobs.ErrNotFound)
+}
+
+func TestTriggerNowRecordsHistory(t *testing.T) {
+	t.Parallel()
+
+	jm := newManager(t)
+	ctx := context.Background()
+
+	err := jm.Register(ctx, "ok", neverCron, func(context.Context) error { return nil })
+	require.NoError(t, err)
+	require.NoError(t, jm.TriggerNow(ctx, "ok"))
+
+	boom := errors.New("boom")
+	err = jm.Register(ctx, "failing", neverCron, func(context.Context) error { return boom })
+	require.NoError(t, err)
+	err = jm.TriggerNow(ctx, "failing")
+	require.ErrorIs(t, err, boom)
+
+	runs, err := jm.LastRuns(ctx, "failing", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, boom.Error(), runs[0].Err)
+
+	_, err = jm.LastRuns(ctx, "missing", 10)
+	require.ErrorIs(t, err, jobs.ErrNotFound)
+}
+
+func TestTriggerNowDeduplicatesConcurrentRun(t *testing.T) {
+	t.Parallel()
+
+	jm := newManager(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	err := jm.Register(ctx, "slow", neverCron, func(context.Context) error {
+		calls.Add(1)
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var firstErr, secondErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		firstErr = jm.TriggerNow(ctx, "slow")
+	}()
+	<-entered
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		secondErr = jm.TriggerNow(ctx, "slow")
+	}()
+	// give the second TriggerNow time to observe the in-flight run before
+	// the first one is released.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestPauseResumeReschedule(t *testing.T) {
+	t.Parallel()
+
+	jm := newManager(t)
+	ctx := context.Background()
+
+	err := jm.Register(ctx, "job", neverCron, func(context.Context) error { return nil })
+	require.NoError(t, err)
+
+	require.NoError(t, jm.Pause("job"))
+	require.NoError(t, jm.Resume("job"))
+	require.ErrorIs(t, jm.Pause("missing"), jobs.ErrNotFound)
+	require.ErrorIs(t, jm.Resume("missing"), jobs.ErrNotFound)
+
+	require.NoError(t, jm.Reschedule(ctx, "job", "0 0 2 1 *"))
+	require.ErrorIs(t, jm.Reschedule(ctx, "missing", neverCron), jobs.ErrNotFound)
+}
+
+func TestHistoryPruning(t *testing.T) {
+	t.Parallel()
+
+	scheduler, err := gocron.NewScheduler()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = scheduler.Shutdown() })
+
+	mdb := newMemDB()
+	jm := jobs.NewManager(scheduler, mdb, 3)
+	ctx := context.Background()
+
+	err = jm.Register(ctx, "job", neverCron, func(context.Context) error { return nil })
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, jm.TriggerNow(ctx, "job"))
+	}
+
+	runs, err := jm.LastRuns(ctx, "job", 100)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+
+	var keys int
+	err = mdb.Scan(ctx, func(db.KV) error {
+		keys++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, keys)
+}
+
+// memDB is a minimal in-memory db.DB so jobs tests don't depend on a real
+// BadgerDB instance. Do serializes like a single-writer transaction, which
+// is all the jobs package relies on.
+type memDB struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemDB() *memDB {
+	return &memDB{data: make(map[string][]byte)}
+}
+
+func (d *memDB) Start(context.Context) error { return nil }
+func (d *memDB) Stop()                       {}
+
+func (d *memDB) Do(ctx context.Context, fn func(db.Tx) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return fn(memTx{db: d})
+}
+
+func (d *memDB) Scan(ctx context.Context, fn func(db.KV) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, v := range d.data {
+		if err := fn(db.KV{Key: []byte(k), Val: v}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type memTx struct {
+	db *memDB
+}
+
+func (tx memTx) Get(key []byte) ([]byte, error) {
+	v, ok := tx.db.data[string(key)]
+	if !ok {
+		return nil, db.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+func (tx memTx) Set(key []byte, val []byte) error {
+	tx.db.data[string(key)] = val
+	return nil
+}
+
+func (tx memTx) Delete(key []byte) error {
+	delete(tx.db.data, string(key))
+	return nil
+}